@@ -0,0 +1,50 @@
+package cord
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// JSONDriver lets callers swap the JSON implementation used on the
+// gateway's hot path (every frame in and out goes through it) for a
+// faster codec such as easyjson, jsoniter, or segmentio/encoding, without
+// forking the package.
+//
+// NewEncoder/NewDecoder aren't called anywhere in this package today -
+// marshalPayload/unmarshalPayload and events.Dispatch only use
+// Marshal/Unmarshal - but they're part of the interface on purpose, kept
+// for drivers that stream rather than buffer a whole message. Removing
+// them is a deliberate scope change from what this interface was asked
+// to provide, not a dead-code cleanup; do it as its own reviewed decision
+// if it happens, not folded into an unrelated commit.
+type JSONDriver interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+	NewEncoder(w io.Writer) JSONEncoder
+	NewDecoder(r io.Reader) JSONDecoder
+}
+
+// JSONEncoder writes successive JSON values to an output stream.
+type JSONEncoder interface {
+	Encode(v interface{}) error
+}
+
+// JSONDecoder reads successive JSON values from an input stream.
+type JSONDecoder interface {
+	Decode(v interface{}) error
+}
+
+// stdJSONDriver is the default JSONDriver, backed by encoding/json.
+type stdJSONDriver struct{}
+
+// Marshal implements JSONDriver.Marshal
+func (stdJSONDriver) Marshal(v interface{}) ([]byte, error) { return json.Marshal(v) }
+
+// Unmarshal implements JSONDriver.Unmarshal
+func (stdJSONDriver) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+// NewEncoder implements JSONDriver.NewEncoder
+func (stdJSONDriver) NewEncoder(w io.Writer) JSONEncoder { return json.NewEncoder(w) }
+
+// NewDecoder implements JSONDriver.NewDecoder
+func (stdJSONDriver) NewDecoder(r io.Reader) JSONDecoder { return json.NewDecoder(r) }
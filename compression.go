@@ -0,0 +1,113 @@
+package cord
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"io"
+)
+
+// Compression selects how the gateway connection is compressed.
+type Compression int
+
+const (
+	// PerFrame zlib-compresses each frame independently. This is the
+	// zero value and default, matching the library's existing behavior.
+	PerFrame Compression = iota
+
+	// None disables compression entirely.
+	None
+
+	// Stream keeps a single zlib stream alive for the life of the
+	// connection (`compress=zlib-stream` on the gateway URL) instead of
+	// reinitializing the decompressor on every frame, matching how the
+	// official gateway streams large READY payloads.
+	Stream
+)
+
+// zlibSyncFlush is the 4-byte suffix zlib appends at a SYNC_FLUSH
+// boundary. Discord flushes one of these at the end of every payload
+// sent over a zlib-stream connection. It never sets BFINAL, so a raw
+// deflate reader fed exactly up to and including this marker always
+// ends in io.ErrUnexpectedEOF trying to read the next block header -
+// that error is the expected, harmless signal that we've reached a
+// flush point, not a real failure.
+var zlibSyncFlush = []byte{0x00, 0x00, 0xff, 0xff}
+
+// maxDictSize is deflate's window size: how much trailing decompressed
+// output has to be kept around as a preset dictionary so backreferences
+// made in a later segment, against bytes compressed in an earlier one,
+// still resolve.
+const maxDictSize = 32 * 1024
+
+// streamDecompressor decodes a zlib-stream gateway connection, where
+// Discord keeps a single zlib stream alive for the connection's life and
+// flushes it after every payload instead of reinitializing compression
+// per-frame. Each flush point is a byte-aligned boundary that can be
+// decoded on its own given the trailing window of previously
+// decompressed bytes as a dictionary, so rather than keep one long-lived
+// reader across frames - which permanently breaks the moment it hits the
+// "unexpected EOF" every SYNC_FLUSH produces - streamDecompressor
+// decodes each segment with a fresh reader and carries the dictionary
+// forward itself. It must be re-created for every new TCP connection,
+// since a fresh connection starts a fresh zlib stream.
+type streamDecompressor struct {
+	buf bytes.Buffer
+
+	// strippedHeader is true once the 2-byte zlib header has been
+	// discarded from buf; everything accumulated after it is a raw
+	// deflate bitstream we decode ourselves.
+	strippedHeader bool
+
+	dict []byte
+}
+
+// newStreamDecompressor creates an empty streamDecompressor.
+func newStreamDecompressor() *streamDecompressor {
+	return &streamDecompressor{}
+}
+
+// Feed appends frame to the accumulated buffer. If the buffer doesn't yet
+// end on a SYNC_FLUSH boundary, it returns (nil, nil) so the caller can
+// wait for more frames. Otherwise it decodes and returns everything
+// accumulated since the last flush.
+func (s *streamDecompressor) Feed(frame []byte) ([]byte, error) {
+	s.buf.Write(frame)
+
+	if !s.strippedHeader {
+		if s.buf.Len() < 2 {
+			return nil, nil
+		}
+		// The 2-byte zlib header (CMF, FLG) only appears once, at the
+		// start of the connection; discard it here rather than via
+		// compress/zlib, which would buffer ahead into bytes we need to
+		// track ourselves for the next flush boundary.
+		s.buf.Next(2)
+		s.strippedHeader = true
+	}
+
+	if s.buf.Len() < len(zlibSyncFlush) {
+		return nil, nil
+	}
+
+	tail := s.buf.Bytes()
+	if !bytes.Equal(tail[len(tail)-len(zlibSyncFlush):], zlibSyncFlush) {
+		return nil, nil
+	}
+
+	fr := flate.NewReaderDict(bytes.NewReader(s.buf.Bytes()), s.dict)
+	defer fr.Close()
+
+	var out bytes.Buffer
+	if _, err := io.Copy(&out, fr); err != nil && err != io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("cord/websocket: decoding zlib-stream segment: %s", err)
+	}
+
+	s.buf.Reset()
+	s.dict = append(s.dict, out.Bytes()...)
+	if len(s.dict) > maxDictSize {
+		s.dict = s.dict[len(s.dict)-maxDictSize:]
+	}
+
+	return out.Bytes(), nil
+}
@@ -0,0 +1,99 @@
+package cord
+
+import (
+	"bytes"
+	"compress/flate"
+	"testing"
+)
+
+// buildStreamFrames compresses each message in turn onto the same
+// flate.Writer with a Flush after each one, returning the raw zlib-stream
+// header followed by one frame per message - i.e. exactly the shape
+// Discord's connection produces, with maxDictSize-style backreferences
+// across segments intact.
+func buildStreamFrames(t *testing.T, messages ...[]byte) [][]byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	fw, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		t.Fatalf("flate.NewWriter: %s", err)
+	}
+
+	frames := [][]byte{{0x78, 0x9c}} // fake 2-byte zlib header
+	for _, msg := range messages {
+		if _, err := fw.Write(msg); err != nil {
+			t.Fatalf("writing segment: %s", err)
+		}
+		if err := fw.Flush(); err != nil {
+			t.Fatalf("flushing segment: %s", err)
+		}
+
+		segment := make([]byte, buf.Len())
+		copy(segment, buf.Bytes())
+		buf.Reset()
+		frames = append(frames, segment)
+	}
+
+	return frames
+}
+
+func TestStreamDecompressorRoundTrip(t *testing.T) {
+	messages := [][]byte{
+		[]byte(`{"op":10,"d":{"heartbeat_interval":41250}}`),
+		[]byte(`{"op":0,"t":"READY","d":{"session_id":"abc"}}`),
+		// Repeats the first message verbatim, so decoding it only comes
+		// out right if the dictionary carried over from that segment.
+		[]byte(`{"op":10,"d":{"heartbeat_interval":41250}}`),
+	}
+	frames := buildStreamFrames(t, messages...)
+
+	d := newStreamDecompressor()
+
+	// A real connection's first binary message carries the zlib header
+	// glued onto the first segment; feed it that way here too.
+	first := append(append([]byte{}, frames[0]...), frames[1]...)
+	out, err := d.Feed(first)
+	if err != nil {
+		t.Fatalf("Feed(first): %s", err)
+	}
+	if !bytes.Equal(out, messages[0]) {
+		t.Fatalf("message 0: got %q, want %q", out, messages[0])
+	}
+
+	for i, frame := range frames[2:] {
+		out, err := d.Feed(frame)
+		if err != nil {
+			t.Fatalf("Feed(%d): %s", i+1, err)
+		}
+		if !bytes.Equal(out, messages[i+1]) {
+			t.Fatalf("message %d: got %q, want %q", i+1, out, messages[i+1])
+		}
+	}
+}
+
+func TestStreamDecompressorWaitsForFlushBoundary(t *testing.T) {
+	frames := buildStreamFrames(t, []byte("hello world, this is a segment"))
+	whole := append(append([]byte{}, frames[0]...), frames[1]...)
+
+	d := newStreamDecompressor()
+
+	// Feed everything but the trailing SYNC_FLUSH marker in one call, so
+	// Feed has to report it's still waiting for more.
+	split := len(whole) - len(zlibSyncFlush)
+	out, err := d.Feed(whole[:split])
+	if err != nil {
+		t.Fatalf("Feed: %s", err)
+	}
+	if out != nil {
+		t.Fatalf("Feed returned %q before the flush boundary arrived", out)
+	}
+
+	out, err = d.Feed(whole[split:])
+	if err != nil {
+		t.Fatalf("Feed: %s", err)
+	}
+	if !bytes.Equal(out, []byte("hello world, this is a segment")) {
+		t.Fatalf("got %q, want %q", out, "hello world, this is a segment")
+	}
+}
@@ -1,6 +1,7 @@
 package cord
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/WatchBeam/cord/events"
@@ -10,9 +11,18 @@ import (
 // the socket are safe for concurrent use.
 type Socket interface {
 	// Send dispatches an event down the Discord socket. It returns an error
-	// if there was any issue in sending it.
+	// if there was any issue in sending it. It blocks until the message is
+	// written or the connection is closed; use SendCtx to bound the wait.
 	Send(op Operation, data json.Marshaler) error
 
+	// SendCtx is like Send, but also aborts waiting for the message to be
+	// written once ctx is done, which matters if the write pump is busy
+	// restarting the connection. ctx also bounds the wait for room in the
+	// queue. Once the message is queued, ctx only bounds the wait: a
+	// message still in the queue when ctx is done is not removed, and is
+	// written when the write pump reaches it.
+	SendCtx(ctx context.Context, op Operation, data json.Marshaler) error
+
 	// On attaches a handler to an event.
 	On(h events.Handler)
 
@@ -26,12 +36,18 @@ type Socket interface {
 	// on the websocket.
 	Errs() <-chan error
 
+	// Start begins connecting to Discord. It's a no-op unless the socket
+	// was created with WsOptions.DeferStart, since New starts the
+	// connection itself otherwise.
+	Start()
+
 	// Frees resources associated with the socket.
 	Close() error
 }
 
 // New creates a connection to the Discord servers. Options may be nil if
-// you want to use the defaults.
+// you want to use the defaults. Unless options.DeferStart is set, New
+// starts connecting before it returns.
 func New(token string, options *WsOptions) Socket {
 	if options == nil {
 		options = &WsOptions{}
@@ -44,7 +60,10 @@ func New(token string, options *WsOptions) Socket {
 		errs:   make(chan error),
 	}
 
-	ws.start()
+	if !options.DeferStart {
+		ws.started = 1
+		ws.start()
+	}
 
 	return ws
 }
@@ -0,0 +1,39 @@
+package cord
+
+// Debugger is notified of gateway lifecycle events and raw frame
+// traffic, for callers that want to log or inspect what the websocket is
+// doing. All methods must be safe to call concurrently, since they're
+// invoked from the handshake, read pump, and write pump goroutines.
+type Debugger interface {
+	// Connecting is called with the URL returned by the GatewayRetriever
+	// right before dialing. In Stream mode the URL actually dialed also
+	// carries the `compress=zlib-stream` query parameter.
+	Connecting(gateway string)
+
+	// Incoming is called with every decompressed frame received from
+	// the gateway, before it's unmarshaled.
+	Incoming(b []byte)
+
+	// Outgoing is called with every frame about to be written to the
+	// gateway, before compression (there is none on the way out).
+	Outgoing(b []byte)
+
+	// Error is called with every error sent on Websocket.Errs, in
+	// addition to it being sent there.
+	Error(err error)
+}
+
+// nilDebugger is the default Debugger: it discards everything.
+type nilDebugger struct{}
+
+// Connecting implements Debugger.Connecting
+func (nilDebugger) Connecting(gateway string) {}
+
+// Incoming implements Debugger.Incoming
+func (nilDebugger) Incoming(b []byte) {}
+
+// Outgoing implements Debugger.Outgoing
+func (nilDebugger) Outgoing(b []byte) {}
+
+// Error implements Debugger.Error
+func (nilDebugger) Error(err error) {}
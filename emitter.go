@@ -0,0 +1,74 @@
+package cord
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/WatchBeam/cord/events"
+)
+
+// emitter fans a dispatched event out to every handler attached for it.
+type emitter struct {
+	mu       sync.Mutex
+	handlers map[string][]events.Handler
+	once     map[string][]events.Handler
+}
+
+// newEmitter creates an empty emitter.
+func newEmitter() emitter {
+	return emitter{
+		handlers: map[string][]events.Handler{},
+		once:     map[string][]events.Handler{},
+	}
+}
+
+// On implements Socket.On
+func (e *emitter) On(h events.Handler) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.handlers[h.Event()] = append(e.handlers[h.Event()], h)
+}
+
+// Once implements Socket.Once
+func (e *emitter) Once(h events.Handler) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.once[h.Event()] = append(e.once[h.Event()], h)
+}
+
+// Off implements Socket.Off
+func (e *emitter) Off(h events.Handler) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	remaining := e.handlers[h.Event()][:0]
+	for _, existing := range e.handlers[h.Event()] {
+		// Handler values built from this package's constructors carry a
+		// func field and are therefore uncomparable with !=; compare
+		// their assigned IDs instead.
+		if existing.ID() != h.ID() {
+			remaining = append(remaining, existing)
+		}
+	}
+	e.handlers[h.Event()] = remaining
+}
+
+// Dispatch invokes every handler attached to event, decoding data with
+// unmarshal, and clears any once-handlers that fired.
+func (e *emitter) Dispatch(event string, unmarshal events.Unmarshal, data json.RawMessage) error {
+	e.mu.Lock()
+	handlers := append([]events.Handler{}, e.handlers[event]...)
+	once := e.once[event]
+	delete(e.once, event)
+	e.mu.Unlock()
+
+	handlers = append(handlers, once...)
+
+	for _, h := range handlers {
+		if err := h.Invoke(unmarshal, data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
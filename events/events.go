@@ -0,0 +1,70 @@
+// Package events defines the Handler interface used to react to events
+// dispatched over the gateway, along with typed constructors for each
+// event the gateway sends.
+package events
+
+import (
+	"encoding/json"
+	"sync/atomic"
+)
+
+// Unmarshal decodes data into v, matching encoding/json.Unmarshal's
+// signature so callers can supply a driver-provided implementation
+// instead of always going through the stdlib.
+type Unmarshal func(data []byte, v interface{}) error
+
+// Handler reacts to a single dispatched event.
+type Handler interface {
+	// Event returns the event name this handler reacts to, e.g. "READY".
+	Event() string
+
+	// Invoke decodes data with unmarshal and calls the handler's callback.
+	Invoke(unmarshal Unmarshal, data json.RawMessage) error
+
+	// ID returns the identity assigned to this handler at construction
+	// time. Every typed constructor in this package builds on handler,
+	// whose fn field makes it non-comparable, so code that needs to
+	// recognize "this handler, specifically" (Socket.Off) must compare
+	// ID() rather than the Handler value itself.
+	ID() uint64
+}
+
+// Event names, as they appear in the `t` field of a Dispatch payload.
+const (
+	ReadyStr             = "READY"
+	ResumedStr           = "RESUMED"
+	VoiceStateUpdateStr  = "VOICE_STATE_UPDATE"
+	VoiceServerUpdateStr = "VOICE_SERVER_UPDATE"
+)
+
+// nextID hands out the monotonic ids that give otherwise-uncomparable
+// handler values an identity.
+var nextID uint64
+
+// handler is the generic Handler implementation every typed constructor
+// in this package builds on.
+type handler struct {
+	id    uint64
+	event string
+	fn    func(unmarshal Unmarshal, data json.RawMessage) error
+}
+
+// newHandler builds a handler for event with a freshly assigned id.
+func newHandler(event string, fn func(unmarshal Unmarshal, data json.RawMessage) error) handler {
+	return handler{
+		id:    atomic.AddUint64(&nextID, 1),
+		event: event,
+		fn:    fn,
+	}
+}
+
+// Event implements Handler.Event
+func (h handler) Event() string { return h.event }
+
+// Invoke implements Handler.Invoke
+func (h handler) Invoke(unmarshal Unmarshal, data json.RawMessage) error {
+	return h.fn(unmarshal, data)
+}
+
+// ID implements Handler.ID
+func (h handler) ID() uint64 { return h.id }
@@ -0,0 +1,19 @@
+package events
+
+import (
+	"encoding/json"
+
+	"github.com/WatchBeam/cord/model"
+)
+
+// Ready builds a Handler that decodes a READY event and calls fn.
+func Ready(fn func(*model.Ready)) Handler {
+	return newHandler(ReadyStr, func(unmarshal Unmarshal, data json.RawMessage) error {
+		r := &model.Ready{}
+		if err := unmarshal(data, r); err != nil {
+			return err
+		}
+		fn(r)
+		return nil
+	})
+}
@@ -0,0 +1,19 @@
+package events
+
+import (
+	"encoding/json"
+
+	"github.com/WatchBeam/cord/model"
+)
+
+// Resumed builds a Handler that decodes a RESUMED event and calls fn.
+func Resumed(fn func(*model.Resumed)) Handler {
+	return newHandler(ResumedStr, func(unmarshal Unmarshal, data json.RawMessage) error {
+		r := &model.Resumed{}
+		if err := unmarshal(data, r); err != nil {
+			return err
+		}
+		fn(r)
+		return nil
+	})
+}
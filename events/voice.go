@@ -0,0 +1,33 @@
+package events
+
+import (
+	"encoding/json"
+
+	"github.com/WatchBeam/cord/model"
+)
+
+// VoiceStateUpdate builds a Handler that decodes a VOICE_STATE_UPDATE
+// event and calls fn.
+func VoiceStateUpdate(fn func(*model.VoiceStateUpdate)) Handler {
+	return newHandler(VoiceStateUpdateStr, func(unmarshal Unmarshal, data json.RawMessage) error {
+		v := &model.VoiceStateUpdate{}
+		if err := unmarshal(data, v); err != nil {
+			return err
+		}
+		fn(v)
+		return nil
+	})
+}
+
+// VoiceServerUpdate builds a Handler that decodes a VOICE_SERVER_UPDATE
+// event and calls fn.
+func VoiceServerUpdate(fn func(*model.VoiceServerUpdate)) Handler {
+	return newHandler(VoiceServerUpdateStr, func(unmarshal Unmarshal, data json.RawMessage) error {
+		v := &model.VoiceServerUpdate{}
+		if err := unmarshal(data, v); err != nil {
+			return err
+		}
+		fn(v)
+		return nil
+	})
+}
@@ -0,0 +1,93 @@
+package cord
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// GatewayRetriever looks up the websocket URL to connect to.
+type GatewayRetriever interface {
+	// Gateway returns the URL of the gateway to connect to.
+	Gateway() (string, error)
+}
+
+// HTTPGatewayRetriever looks up the gateway URL by hitting Discord's
+// unauthenticated `/gateway` endpoint.
+type HTTPGatewayRetriever struct {
+	Client  *http.Client
+	BaseURL string
+}
+
+// Gateway implements GatewayRetriever.Gateway
+func (h HTTPGatewayRetriever) Gateway() (string, error) {
+	resp, err := h.Client.Get(h.BaseURL + "/gateway")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		URL string `json:"url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+
+	return body.URL, nil
+}
+
+// SessionStartLimit describes how many more sessions a bot may start
+// within the current rate limit window, as reported by `/gateway/bot`.
+type SessionStartLimit struct {
+	Total      int `json:"total"`
+	Remaining  int `json:"remaining"`
+	ResetAfter int `json:"reset_after"`
+}
+
+// BotGatewayRetriever looks up the gateway URL, recommended shard count,
+// and identify budget by hitting Discord's authenticated `/gateway/bot`
+// endpoint. Unlike HTTPGatewayRetriever, it requires a bot token.
+type BotGatewayRetriever struct {
+	Client  *http.Client
+	BaseURL string
+	Token   string
+}
+
+// GatewayBot hits `/gateway/bot` and returns the gateway URL, the
+// recommended shard count, and the current session start limit.
+func (b BotGatewayRetriever) GatewayBot() (url string, shards int, limit SessionStartLimit, err error) {
+	req, err := http.NewRequest("GET", b.BaseURL+"/gateway/bot", nil)
+	if err != nil {
+		return "", 0, limit, err
+	}
+	req.Header.Set("Authorization", "Bot "+b.Token)
+
+	resp, err := b.Client.Do(req)
+	if err != nil {
+		return "", 0, limit, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, limit, fmt.Errorf("cord: /gateway/bot returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		URL               string            `json:"url"`
+		Shards            int               `json:"shards"`
+		SessionStartLimit SessionStartLimit `json:"session_start_limit"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", 0, limit, err
+	}
+
+	return body.URL, body.Shards, body.SessionStartLimit, nil
+}
+
+// Gateway implements GatewayRetriever.Gateway, discarding the shard count
+// and session start limit. Use GatewayBot directly to access those.
+func (b BotGatewayRetriever) Gateway() (string, error) {
+	url, _, _, err := b.GatewayBot()
+	return url, err
+}
@@ -0,0 +1,61 @@
+package model
+
+import "encoding/json"
+
+// Handshake is sent as the data of an Identify operation to authenticate
+// a new session with the gateway.
+type Handshake struct {
+	// Token is the bot or user token to authenticate with.
+	Token string
+
+	// Compress indicates whether the client supports payload compression.
+	Compress bool
+
+	// Properties are connection properties reported to Discord, such as
+	// the OS and library name.
+	Properties HandshakeProperties
+
+	// Shard holds the [shard id, num shards] pair for sharded connections.
+	// It's omitted from the Identify payload when nil, which tells
+	// Discord the session isn't sharded.
+	Shard *[2]int
+
+	// Intents is the bitfield of gateway intents to request. Current
+	// gateway versions require this to be set to at least one intent.
+	Intents Intents
+}
+
+// HandshakeProperties are sent as part of the Handshake to tell Discord
+// about the connecting client.
+type HandshakeProperties struct {
+	OS      string
+	Browser string
+}
+
+// MarshalJSON implements json.Marshaler.
+func (h *Handshake) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Token      string              `json:"token"`
+		Compress   bool                `json:"compress"`
+		Properties HandshakeProperties `json:"properties"`
+		Shard      *[2]int             `json:"shard,omitempty"`
+		Intents    Intents             `json:"intents"`
+	}{
+		Token:      h.Token,
+		Compress:   h.Compress,
+		Properties: h.Properties,
+		Shard:      h.Shard,
+		Intents:    h.Intents,
+	})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (h HandshakeProperties) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		OS      string `json:"$os"`
+		Browser string `json:"$browser"`
+	}{
+		OS:      h.OS,
+		Browser: h.Browser,
+	})
+}
@@ -0,0 +1,63 @@
+package model
+
+// Intents is a bitfield of gateway intents, sent as part of a Handshake
+// to tell Discord which events the connection wants to receive. Discord
+// requires an Identify payload to specify its intents; connecting
+// without any will be rejected by current gateway versions.
+type Intents int
+
+const (
+	// IntentGuilds covers guild create/update/delete and channel/role/
+	// thread lifecycle events.
+	IntentGuilds Intents = 1 << 0
+
+	// IntentGuildMembers covers guild member add/update/remove. This is
+	// a privileged intent and must be enabled for the bot in the
+	// Discord developer portal before Discord will grant it.
+	IntentGuildMembers Intents = 1 << 1
+
+	// IntentGuildBans covers guild ban add/remove.
+	IntentGuildBans Intents = 1 << 2
+
+	// IntentGuildEmojis covers guild emoji and sticker updates.
+	IntentGuildEmojis Intents = 1 << 3
+
+	// IntentGuildIntegrations covers guild integration updates.
+	IntentGuildIntegrations Intents = 1 << 4
+
+	// IntentGuildWebhooks covers guild webhook updates.
+	IntentGuildWebhooks Intents = 1 << 5
+
+	// IntentGuildInvites covers guild invite create/delete.
+	IntentGuildInvites Intents = 1 << 6
+
+	// IntentGuildVoiceStates covers voice state updates, required to
+	// receive the events the voice subpackage needs to join a channel.
+	IntentGuildVoiceStates Intents = 1 << 7
+
+	// IntentGuildPresences covers presence updates. This is a
+	// privileged intent and must be enabled for the bot in the Discord
+	// developer portal before Discord will grant it.
+	IntentGuildPresences Intents = 1 << 8
+
+	// IntentGuildMessages covers guild message create/update/delete.
+	IntentGuildMessages Intents = 1 << 9
+
+	// IntentGuildMessageReactions covers guild message reaction add/
+	// remove.
+	IntentGuildMessageReactions Intents = 1 << 10
+
+	// IntentGuildMessageTyping covers guild typing start events.
+	IntentGuildMessageTyping Intents = 1 << 11
+
+	// IntentDirectMessages covers direct message create/update/delete.
+	IntentDirectMessages Intents = 1 << 12
+
+	// IntentDirectMessageReactions covers direct message reaction add/
+	// remove.
+	IntentDirectMessageReactions Intents = 1 << 13
+
+	// IntentDirectMessageTyping covers direct message typing start
+	// events.
+	IntentDirectMessageTyping Intents = 1 << 14
+)
@@ -0,0 +1,14 @@
+package model
+
+// Ready is dispatched in response to a successful Identify, carrying the
+// session details needed to resume later.
+type Ready struct {
+	HeartbeatInterval uint   `json:"heartbeat_interval"`
+	SessionID         string `json:"session_id"`
+	User              User   `json:"user"`
+}
+
+// User is the bot's own user object, as reported on Ready.
+type User struct {
+	ID string `json:"id"`
+}
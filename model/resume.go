@@ -0,0 +1,23 @@
+package model
+
+import "encoding/json"
+
+// Resume is sent to continue a previously disconnected session.
+type Resume struct {
+	Token     string
+	SessionID string
+	Sequence  uint64
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r *Resume) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Token     string `json:"token"`
+		SessionID string `json:"session_id"`
+		Sequence  uint64 `json:"seq"`
+	}{
+		Token:     r.Token,
+		SessionID: r.SessionID,
+		Sequence:  r.Sequence,
+	})
+}
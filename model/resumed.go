@@ -0,0 +1,7 @@
+package model
+
+// Resumed is dispatched in response to a successful Resume, carrying the
+// heartbeat interval for the resumed session.
+type Resumed struct {
+	HeartbeatInterval uint `json:"heartbeat_interval"`
+}
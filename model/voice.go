@@ -0,0 +1,46 @@
+package model
+
+import "encoding/json"
+
+// UpdateVoiceState is sent through the main gateway (op 4) to join, move
+// between, or leave a voice channel. ChannelID is a pointer so that
+// passing nil disconnects from voice entirely, mirroring how upstream
+// libraries model the nullable `channel_id` field.
+type UpdateVoiceState struct {
+	GuildID   string
+	ChannelID *string
+	SelfMute  bool
+	SelfDeaf  bool
+}
+
+// MarshalJSON implements json.Marshaler.
+func (u *UpdateVoiceState) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		GuildID   string  `json:"guild_id"`
+		ChannelID *string `json:"channel_id"`
+		SelfMute  bool    `json:"self_mute"`
+		SelfDeaf  bool    `json:"self_deaf"`
+	}{
+		GuildID:   u.GuildID,
+		ChannelID: u.ChannelID,
+		SelfMute:  u.SelfMute,
+		SelfDeaf:  u.SelfDeaf,
+	})
+}
+
+// VoiceStateUpdate is dispatched when a user's voice state changes,
+// including our own after sending an UpdateVoiceState.
+type VoiceStateUpdate struct {
+	GuildID   string  `json:"guild_id"`
+	ChannelID *string `json:"channel_id"`
+	UserID    string  `json:"user_id"`
+	SessionID string  `json:"session_id"`
+}
+
+// VoiceServerUpdate is dispatched after UpdateVoiceState with the voice
+// endpoint and token to use for the voice websocket handshake.
+type VoiceServerUpdate struct {
+	Token    string `json:"token"`
+	GuildID  string `json:"guild_id"`
+	Endpoint string `json:"endpoint"`
+}
@@ -0,0 +1,30 @@
+package cord
+
+// Operation is a gateway opcode, sent as part of every Payload to
+// indicate how its data should be interpreted.
+type Operation int
+
+const (
+	// Dispatch is sent by Discord for dispatched events.
+	Dispatch Operation = 0
+	// Heartbeat is sent and received to keep the connection alive.
+	Heartbeat Operation = 1
+	// Identify is sent to start a new session during the handshake.
+	Identify Operation = 2
+	// PresenceUpdate is sent to update the client's presence.
+	PresenceUpdate Operation = 3
+	// VoiceStateUpdate is sent to join, move between, or leave a voice
+	// channel.
+	VoiceStateUpdate Operation = 4
+	// Resume is sent to resume a previously disconnected session.
+	Resume Operation = 6
+	// Reconnect is received to tell the client to reconnect and resume.
+	Reconnect Operation = 7
+	// InvalidSession is received when the session id is invalid.
+	InvalidSession Operation = 9
+	// Hello is received immediately after connecting, containing the
+	// heartbeat interval.
+	Hello Operation = 10
+	// HeartbeatAck is received in acknowledgment of a Heartbeat.
+	HeartbeatAck Operation = 11
+)
@@ -0,0 +1,105 @@
+package cord
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// PacemakerDeadError is surfaced through restart when two consecutive
+// heartbeats go unacknowledged. Unlike a read-deadline timeout, which can
+// take up to WsOptions.Timeout to notice a dead peer, this fires within
+// three heartbeat intervals of the server going quiet at the protocol
+// level: on the tick after the second unacknowledged heartbeat.
+type PacemakerDeadError struct{}
+
+// Error implements error.
+func (PacemakerDeadError) Error() string {
+	return "cord/websocket: peer missed two consecutive heartbeat acks"
+}
+
+// pacemaker drives the heartbeat schedule for a single websocket
+// connection. It must be re-created for every new connection, and is
+// started and stopped alongside that connection's read and write pumps.
+type pacemaker struct {
+	interval time.Duration
+
+	beat chan struct{}
+	dead chan struct{}
+	stop chan struct{}
+	once sync.Once
+
+	acked  int32 // atomically updated; 0 once a heartbeat is sent, set back to 1 by Echo
+	misses int
+}
+
+// minPacemakerInterval is the floor newPacemaker clamps interval to.
+// time.NewTicker panics on a non-positive duration, and interval usually
+// comes straight off the wire (model.Ready/model.Resumed's
+// HeartbeatInterval); establishSocketConnection already rejects a zero
+// value as a failed handshake before it gets here, but Pace must not be
+// able to panic the process no matter what its caller passes it.
+const minPacemakerInterval = 50 * time.Millisecond
+
+// newPacemaker creates a pacemaker that ticks every interval, clamped to
+// minPacemakerInterval.
+func newPacemaker(interval time.Duration) *pacemaker {
+	if interval < minPacemakerInterval {
+		interval = minPacemakerInterval
+	}
+
+	return &pacemaker{
+		interval: interval,
+		beat:     make(chan struct{}),
+		dead:     make(chan struct{}),
+		stop:     make(chan struct{}),
+		acked:    1,
+	}
+}
+
+// Pace runs the heartbeat schedule until Stop is called or two
+// consecutive heartbeats go unacknowledged, in which case it closes the
+// channel returned by Dead and exits. It should be run in its own
+// goroutine.
+func (p *pacemaker) Pace() {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if atomic.SwapInt32(&p.acked, 0) == 0 {
+				p.misses++
+				if p.misses >= 2 {
+					close(p.dead)
+					return
+				}
+			} else {
+				p.misses = 0
+			}
+
+			select {
+			case p.beat <- struct{}{}:
+			case <-p.stop:
+				return
+			}
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// Echo records that a HeartbeatAck (op 11) was received, clearing the
+// miss count ahead of the next tick.
+func (p *pacemaker) Echo() { atomic.StoreInt32(&p.acked, 1) }
+
+// Stop halts the pacemaker. It is safe to call more than once.
+func (p *pacemaker) Stop() { p.once.Do(func() { close(p.stop) }) }
+
+// Beat yields a value every time a heartbeat should be written to the
+// socket. The caller is responsible for the actual write, since only the
+// write pump's goroutine is allowed to touch the websocket connection.
+func (p *pacemaker) Beat() <-chan struct{} { return p.beat }
+
+// Dead is closed once two consecutive heartbeats have gone unacknowledged.
+func (p *pacemaker) Dead() <-chan struct{} { return p.dead }
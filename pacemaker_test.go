@@ -0,0 +1,82 @@
+package cord
+
+import (
+	"testing"
+	"time"
+)
+
+const pacemakerTestInterval = 10 * time.Millisecond
+
+func TestPacemakerDiesAfterTwoMissedAcks(t *testing.T) {
+	p := newPacemaker(pacemakerTestInterval)
+	go p.Pace()
+	defer p.Stop()
+
+	// Drain every beat without ever Echo-ing one back, like a write pump
+	// stuck unable to reach the server would: the pacemaker only notices
+	// a miss on the next tick, not on Beat going unread.
+	drained := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-p.Beat():
+			case <-drained:
+				return
+			}
+		}
+	}()
+	defer close(drained)
+
+	select {
+	case <-p.Dead():
+	case <-time.After(time.Second):
+		t.Fatalf("pacemaker didn't die after two unacknowledged heartbeats")
+	}
+}
+
+func TestPacemakerSurvivesAckedHeartbeats(t *testing.T) {
+	p := newPacemaker(pacemakerTestInterval)
+	go p.Pace()
+	defer p.Stop()
+
+	for i := 0; i < 5; i++ {
+		select {
+		case <-p.Dead():
+			t.Fatalf("pacemaker died on round %d despite every heartbeat being acked", i)
+		case <-p.Beat():
+			p.Echo()
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for heartbeat %d", i)
+		}
+	}
+}
+
+func TestNewPacemakerClampsNonPositiveInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		p := newPacemaker(interval)
+		if p.interval <= 0 {
+			t.Fatalf("newPacemaker(%s).interval = %s, want a positive clamp", interval, p.interval)
+		}
+
+		// The clamp is only useful if Pace itself doesn't panic building
+		// the ticker.
+		go p.Pace()
+		p.Stop()
+	}
+}
+
+func TestPacemakerStopIsIdempotentAndHaltsBeats(t *testing.T) {
+	p := newPacemaker(pacemakerTestInterval)
+	go p.Pace()
+
+	p.Stop()
+	p.Stop() // must not panic
+
+	select {
+	case <-p.Beat():
+		t.Fatalf("pacemaker kept beating after Stop")
+	case <-p.Dead():
+		t.Fatalf("Stop should halt the pacemaker quietly, not report it as Dead")
+	case <-time.After(pacemakerTestInterval * 5):
+	}
+}
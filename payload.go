@@ -0,0 +1,59 @@
+package cord
+
+import "encoding/json"
+
+// Payload is the envelope every gateway frame is wrapped in: an opcode,
+// an optional sequence number and event name for dispatched events, and
+// the operation-specific data.
+type Payload struct {
+	Operation Operation
+	Sequence  uint64
+	Event     string
+	Data      json.RawMessage
+
+	// driver (de)serializes this Payload. It defaults to the stdlib when
+	// nil, so a zero-value Payload remains usable on its own.
+	driver JSONDriver
+}
+
+// payloadFields mirrors Payload's wire shape, kept separate so marshaling
+// it doesn't recurse back into Payload's own MarshalJSON/UnmarshalJSON.
+type payloadFields struct {
+	Operation Operation       `json:"op"`
+	Sequence  uint64          `json:"s,omitempty"`
+	Event     string          `json:"t,omitempty"`
+	Data      json.RawMessage `json:"d,omitempty"`
+}
+
+func (p *Payload) driverOrDefault() JSONDriver {
+	if p.driver != nil {
+		return p.driver
+	}
+	return stdJSONDriver{}
+}
+
+// MarshalJSON implements json.Marshaler, using the driver this Payload
+// was constructed with.
+func (p *Payload) MarshalJSON() ([]byte, error) {
+	return p.driverOrDefault().Marshal(payloadFields{
+		Operation: p.Operation,
+		Sequence:  p.Sequence,
+		Event:     p.Event,
+		Data:      p.Data,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler, using the driver this
+// Payload was constructed with.
+func (p *Payload) UnmarshalJSON(b []byte) error {
+	var fields payloadFields
+	if err := p.driverOrDefault().Unmarshal(b, &fields); err != nil {
+		return err
+	}
+
+	p.Operation = fields.Operation
+	p.Sequence = fields.Sequence
+	p.Event = fields.Event
+	p.Data = fields.Data
+	return nil
+}
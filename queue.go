@@ -0,0 +1,120 @@
+package cord
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+)
+
+// errQueueClosed is returned by queue.Push once the queue has been
+// closed, e.g. because the wsConn it belonged to was closed or replaced
+// by a reconnect.
+var errQueueClosed = errors.New("cord/websocket: queue is closed")
+
+// queuedMessage pairs an outbound payload with the channel its write
+// result is delivered on, once writePump gets around to sending it.
+type queuedMessage struct {
+	data   json.Marshaler
+	result chan error
+}
+
+// queue is a FIFO of queuedMessages waiting to be written to the gateway
+// by writePump. It's forked across reconnects (see wsConn.Fork) so that
+// messages queued while a connection is down aren't dropped, and closed
+// when the owning wsConn is closed so Pushes blocked on a full queue fail
+// instead of hanging forever.
+//
+// Push only holds q.mu for the quick "are we closed" check, never across
+// the blocking send itself. Close and Fork need that same lock to flip
+// closed; if Push held it across a send that can block
+// forever - an unbounded caller (Send uses context.Background) filling a
+// full queue nobody's draining because the connection is down - Close
+// and Fork would wedge right along with it, and restart() calls Fork as
+// its very first line. Close/Fork instead signal over done, which Push's
+// select also watches.
+type queue struct {
+	mu     sync.Mutex
+	closed bool
+	done   chan struct{}
+	ch     chan *queuedMessage
+}
+
+// newQueue creates an empty queue.
+func newQueue() *queue {
+	return &queue{
+		ch:   make(chan *queuedMessage, 64),
+		done: make(chan struct{}),
+	}
+}
+
+// Push enqueues msg, blocking until there's room, the queue is closed, or
+// ctx is done.
+func (q *queue) Push(ctx context.Context, msg *queuedMessage) error {
+	q.mu.Lock()
+	closed := q.closed
+	q.mu.Unlock()
+	if closed {
+		return errQueueClosed
+	}
+
+	select {
+	case q.ch <- msg:
+		return nil
+	case <-q.done:
+		return errQueueClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Poll returns the channel writePump reads queued messages from.
+func (q *queue) Poll() <-chan *queuedMessage { return q.ch }
+
+// Done is closed once Close is called, so writePump - selecting on it
+// alongside Poll - notices a closed queue instead of waiting on it
+// forever.
+func (q *queue) Done() <-chan struct{} { return q.done }
+
+// Close marks the queue closed, failing further Pushes and closing Done,
+// without blocking on anything a concurrent Push might be doing.
+func (q *queue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return
+	}
+	q.closed = true
+	close(q.done)
+}
+
+// Fork creates a new queue carrying over any messages already buffered in
+// q, so a reconnect doesn't drop outbound messages that hadn't been sent
+// yet. q is left closed.
+//
+// A Push racing concurrently with Fork can still land its message in q
+// right after this finishes draining it, in which case that message is
+// lost rather than carried over. Closing the gap fully would mean
+// serializing Push's send under the same lock Fork holds while draining -
+// exactly the kind of indefinite block under a lock this type exists to
+// avoid.
+func (q *queue) Fork() *queue {
+	next := newQueue()
+
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.done)
+	}
+	q.mu.Unlock()
+
+	for {
+		select {
+		case msg := <-q.ch:
+			next.ch <- msg
+		default:
+			return next
+		}
+	}
+}
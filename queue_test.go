@@ -0,0 +1,39 @@
+package cord
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestQueueForkDoesntDeadlockOnFullQueue guards against a Push that's
+// parked in its blocking select (queue full, nobody draining it, like a
+// connection that's down) wedging a concurrent Fork/Close forever. Fork
+// is restart()'s very first call, so a hang here used to wedge the whole
+// Websocket.
+func TestQueueForkDoesntDeadlockOnFullQueue(t *testing.T) {
+	q := newQueue()
+
+	for i := 0; i < cap(q.ch); i++ {
+		if err := q.Push(context.Background(), &queuedMessage{result: make(chan error, 1)}); err != nil {
+			t.Fatalf("filling the queue: %s", err)
+		}
+	}
+
+	go q.Push(context.Background(), &queuedMessage{result: make(chan error, 1)})
+	// Give the above Push a moment to actually park in its select before
+	// racing Fork against it.
+	time.Sleep(10 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		q.Fork()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Fork deadlocked against a Push blocked on a full queue")
+	}
+}
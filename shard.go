@@ -0,0 +1,186 @@
+package cord
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/WatchBeam/cord/events"
+	"github.com/WatchBeam/cord/model"
+)
+
+// identifyStagger is the minimum delay Discord requires between successive
+// shard identifies.
+const identifyStagger = 5 * time.Second
+
+// ShardManager owns a fixed number of Websocket connections, one per
+// shard, and presents them as a single logical Socket-like surface: it
+// multiplexes their errors into one channel and fans handler
+// registration out to every shard.
+type ShardManager struct {
+	shards []*Websocket
+	errs   chan error
+	done   chan struct{}
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewShardManager spins up `count` shards for the given token, staggering
+// their identifies by identifyStagger to respect Discord's per-shard rate
+// limit. If count is 0, the shard count is looked up via options.Gateway
+// when it's a BotGatewayRetriever; the manager refuses to start if doing
+// so would exceed the remaining identify budget. Staggering happens in
+// the background, so NewShardManager returns as soon as every shard is
+// built rather than blocking for identifyStagger * (count-1).
+func NewShardManager(token string, count int, options *WsOptions) (*ShardManager, error) {
+	if options == nil {
+		options = &WsOptions{}
+	}
+
+	if options.Handshake == nil {
+		options.Handshake = &model.Handshake{}
+	}
+
+	if count == 0 {
+		retriever, ok := options.Gateway.(BotGatewayRetriever)
+		if !ok {
+			count = 1
+		} else {
+			_, shards, limit, err := retriever.GatewayBot()
+			if err != nil {
+				return nil, err
+			}
+			if limit.Remaining < shards {
+				return nil, fmt.Errorf(
+					"cord: identify budget exhausted: need %d sessions, %d remaining (resets in %ds)",
+					shards, limit.Remaining, limit.ResetAfter,
+				)
+			}
+			count = shards
+		}
+	}
+
+	sm := &ShardManager{errs: make(chan error), done: make(chan struct{})}
+	for id := 0; id < count; id++ {
+		shardOpts := *options
+		// The manager - not the caller - owns staggering identifies, so
+		// every shard is built deferred and started from startStaggered
+		// regardless of what the caller set here.
+		shardOpts.DeferStart = true
+		handshake := *options.Handshake
+		handshake.Shard = &[2]int{id, count}
+		shardOpts.Handshake = &handshake
+
+		ws := New(token, &shardOpts).(*Websocket)
+		sm.shards = append(sm.shards, ws)
+		sm.relayErrs(ws)
+	}
+
+	go sm.startStaggered()
+
+	return sm, nil
+}
+
+// startStaggered calls Start on each shard identifyStagger apart, to
+// respect Discord's per-shard identify rate limit, without blocking
+// NewShardManager's caller for however long a realistic shard count
+// takes to fully stagger. It checks closed under the same lock Close
+// holds while closing shards, so a Close that runs mid-stagger is
+// guaranteed either to have already closed a shard before this starts
+// it, or to stop this loop before it starts any more.
+func (s *ShardManager) startStaggered() {
+	for id, ws := range s.shards {
+		if id > 0 {
+			time.Sleep(identifyStagger)
+		}
+
+		s.mu.Lock()
+		closed := s.closed
+		if !closed {
+			ws.Start()
+		}
+		s.mu.Unlock()
+
+		if closed {
+			return
+		}
+	}
+}
+
+// relayErrs forwards errors from a single shard's Errs() channel into the
+// ShardManager's unified error channel until the shard's errors dry up or
+// the manager is closed. Without watching done, this goroutine would leak
+// forever after Close: Websocket.Close doesn't close errs (closing a
+// channel other goroutines might still be sending on is its own hazard),
+// so a bare `for err := range ws.Errs()` never returns on its own.
+func (s *ShardManager) relayErrs(ws *Websocket) {
+	go func() {
+		for {
+			select {
+			case err, ok := <-ws.Errs():
+				if !ok {
+					return
+				}
+				select {
+				case s.errs <- err:
+				case <-s.done:
+					return
+				}
+			case <-s.done:
+				return
+			}
+		}
+	}()
+}
+
+// On attaches a handler to every shard.
+func (s *ShardManager) On(h events.Handler) {
+	for _, ws := range s.shards {
+		ws.On(h)
+	}
+}
+
+// Once attaches h as a once-handler on every shard, so it's called once
+// on each shard that sees the event, not only on the first one.
+func (s *ShardManager) Once(h events.Handler) {
+	for _, ws := range s.shards {
+		ws.Once(h)
+	}
+}
+
+// Off detaches a previously-attached handler from every shard.
+func (s *ShardManager) Off(h events.Handler) {
+	for _, ws := range s.shards {
+		ws.Off(h)
+	}
+}
+
+// Errs returns a channel of errors which may occur asynchronously on any
+// of the managed shards.
+func (s *ShardManager) Errs() <-chan error { return s.errs }
+
+// Shards returns the underlying per-shard sockets, in shard id order.
+func (s *ShardManager) Shards() []*Websocket { return s.shards }
+
+// Close frees resources associated with every shard, including ones
+// startStaggered hasn't started yet: those are marked closed before
+// startStaggered can reach them, so it skips starting new connections on
+// a manager the caller already considers shut down.
+func (s *ShardManager) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.closed {
+		s.closed = true
+		close(s.done)
+	}
+
+	var firstErr error
+	for _, ws := range s.shards {
+		if err := ws.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,34 @@
+package cord
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+// TestRelayErrsStopsOnClose guards against relayErrs leaking a goroutine
+// per shard forever after ShardManager.Close: Websocket.Close doesn't
+// close errs (closing a channel other goroutines might still be sending
+// on is its own hazard), so a relayErrs that only watched ws.Errs() via
+// `for err := range ws.Errs()` would block in that range forever once
+// the shard stops producing errors, instead of noticing the manager shut
+// down.
+func TestRelayErrsStopsOnClose(t *testing.T) {
+	ws := &Websocket{errs: make(chan error)}
+	sm := &ShardManager{errs: make(chan error), done: make(chan struct{})}
+
+	before := runtime.NumGoroutine()
+	sm.relayErrs(ws)
+	close(sm.done)
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if runtime.NumGoroutine() <= before {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	t.Fatalf("relayErrs's goroutine didn't exit after the manager closed (goroutines: %d, started at %d)",
+		runtime.NumGoroutine(), before)
+}
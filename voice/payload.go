@@ -0,0 +1,74 @@
+package voice
+
+import "encoding/json"
+
+// operation is a voice gateway opcode, distinct from and unrelated to the
+// main gateway's Operation space.
+type operation int
+
+const (
+	opIdentify           operation = 0
+	opSelectProtocol     operation = 1
+	opReady              operation = 2
+	opHeartbeat          operation = 3
+	opSessionDescription operation = 4
+	opSpeaking           operation = 5
+	opHeartbeatAck       operation = 6
+	opResume             operation = 7
+	opHello              operation = 8
+)
+
+// payload is the envelope every voice gateway frame is wrapped in.
+type payload struct {
+	Operation operation       `json:"op"`
+	Data      json.RawMessage `json:"d"`
+}
+
+func marshalPayload(op operation, data interface{}) ([]byte, error) {
+	d, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(payload{Operation: op, Data: d})
+}
+
+type identifyData struct {
+	ServerID  string `json:"server_id"`
+	UserID    string `json:"user_id"`
+	SessionID string `json:"session_id"`
+	Token     string `json:"token"`
+}
+
+type readyData struct {
+	SSRC  uint32   `json:"ssrc"`
+	IP    string   `json:"ip"`
+	Port  int      `json:"port"`
+	Modes []string `json:"modes"`
+}
+
+type selectProtocolData struct {
+	Protocol string                 `json:"protocol"`
+	Data     selectProtocolDataBody `json:"data"`
+}
+
+type selectProtocolDataBody struct {
+	Address string `json:"address"`
+	Port    int    `json:"port"`
+	Mode    string `json:"mode"`
+}
+
+type sessionDescriptionData struct {
+	Mode      string   `json:"mode"`
+	SecretKey [32]byte `json:"secret_key"`
+}
+
+type speakingData struct {
+	Speaking bool   `json:"speaking"`
+	Delay    int    `json:"delay"`
+	SSRC     uint32 `json:"ssrc"`
+}
+
+type helloData struct {
+	HeartbeatInterval int `json:"heartbeat_interval"`
+}
@@ -0,0 +1,53 @@
+package voice
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+)
+
+// discoverIP performs Discord's UDP IP discovery: it sends a packet
+// containing our SSRC to the voice server and parses the padded response
+// for the external address and port we're reachable on.
+func discoverIP(conn *net.UDPConn, ssrc uint32) (addr string, port int, err error) {
+	packet := make([]byte, 74)
+	binary.BigEndian.PutUint16(packet[0:2], 1)  // request
+	binary.BigEndian.PutUint16(packet[2:4], 70) // body length
+	binary.BigEndian.PutUint32(packet[4:8], ssrc)
+
+	if _, err := conn.Write(packet); err != nil {
+		return "", 0, fmt.Errorf("cord/voice: ip discovery write: %s", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	resp := make([]byte, 74)
+	n, err := conn.Read(resp)
+	if err != nil {
+		return "", 0, fmt.Errorf("cord/voice: ip discovery read: %s", err)
+	}
+	if n < 74 {
+		return "", 0, fmt.Errorf("cord/voice: ip discovery response too short (%d bytes)", n)
+	}
+
+	// Bytes 8:72 hold the null-terminated address string, 72:74 the port.
+	end := 8
+	for end < 72 && resp[end] != 0 {
+		end++
+	}
+
+	addr = string(resp[8:end])
+	port = int(binary.BigEndian.Uint16(resp[72:74]))
+	return addr, port, nil
+}
+
+// rtpHeader builds the 12-byte RTP header preceding every voice packet.
+func rtpHeader(sequence uint16, timestamp uint32, ssrc uint32) []byte {
+	header := make([]byte, 12)
+	header[0] = 0x80
+	header[1] = 0x78
+	binary.BigEndian.PutUint16(header[2:4], sequence)
+	binary.BigEndian.PutUint32(header[4:8], timestamp)
+	binary.BigEndian.PutUint32(header[8:12], ssrc)
+	return header
+}
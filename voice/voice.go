@@ -0,0 +1,324 @@
+// Package voice implements Discord's voice websocket and UDP RTP flow on
+// top of the main cord.Socket abstraction.
+package voice
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/WatchBeam/cord"
+	"github.com/WatchBeam/cord/events"
+	"github.com/WatchBeam/cord/model"
+	"github.com/gorilla/websocket"
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+const handshakeTimeout = 10 * time.Second
+
+// optionsProvider is implemented by *cord.Websocket, letting us reuse its
+// dialer and own user id without widening the Socket interface. Its
+// Backoff and Debugger are not used: the voice websocket is dialed once,
+// without retries, and its frames are not reported to the Debugger.
+type optionsProvider interface {
+	Options() *cord.WsOptions
+
+	// SelfUserID returns the bot's own user id, used to tell our voice
+	// state/server updates apart from other members' in the same guild.
+	SelfUserID() string
+}
+
+// VoiceConn is a connection to a single guild's voice server, obtained by
+// calling Connect.
+type VoiceConn struct {
+	ws   *websocket.Conn
+	udp  *net.UDPConn
+	ssrc uint32
+
+	secretKey [32]byte
+	sequence  uint16
+	timestamp uint32
+
+	closed int32
+}
+
+// Connect joins the voice channel identified by guildID/channelID on the
+// given session, performs the full voice handshake, and returns a ready
+// to use VoiceConn. VoiceConn.Close tears down the voice connection but
+// sends no voice state update; call Disconnect to leave the channel.
+func Connect(session cord.Socket, guildID, channelID string) (*VoiceConn, error) {
+	opts := &cord.WsOptions{}
+	var selfID string
+	if p, ok := session.(optionsProvider); ok {
+		opts = p.Options()
+		selfID = p.SelfUserID()
+	}
+
+	stateCh := make(chan *model.VoiceStateUpdate, 1)
+	serverCh := make(chan *model.VoiceServerUpdate, 1)
+
+	// On, not Once: Once discards the one-shot slot on the very next
+	// dispatch of the event name regardless of whether GuildID/UserID
+	// matched, so any unrelated voice activity elsewhere - a near
+	// certainty once a bot is in more than one guild - would consume it
+	// before the real update arrived. Stay subscribed until a match is
+	// seen, then unregister ourselves.
+	var stateHandler, serverHandler events.Handler
+	stateHandler = events.VoiceStateUpdate(func(v *model.VoiceStateUpdate) {
+		if v.GuildID != guildID || v.UserID != selfID {
+			return
+		}
+		session.Off(stateHandler)
+		select {
+		case stateCh <- v:
+		default:
+		}
+	})
+	serverHandler = events.VoiceServerUpdate(func(v *model.VoiceServerUpdate) {
+		if v.GuildID != guildID {
+			return
+		}
+		session.Off(serverHandler)
+		select {
+		case serverCh <- v:
+		default:
+		}
+	})
+	session.On(stateHandler)
+	session.On(serverHandler)
+
+	cid := channelID
+	if err := session.Send(cord.VoiceStateUpdate, &model.UpdateVoiceState{
+		GuildID:   guildID,
+		ChannelID: &cid,
+	}); err != nil {
+		session.Off(stateHandler)
+		session.Off(serverHandler)
+		return nil, fmt.Errorf("cord/voice: sending voice state update: %s", err)
+	}
+
+	var state *model.VoiceStateUpdate
+	var server *model.VoiceServerUpdate
+	timeout := time.After(handshakeTimeout)
+	for state == nil || server == nil {
+		select {
+		case state = <-stateCh:
+		case server = <-serverCh:
+		case <-timeout:
+			session.Off(stateHandler)
+			session.Off(serverHandler)
+			return nil, fmt.Errorf("cord/voice: timed out waiting for voice state/server update")
+		}
+	}
+
+	dialer := opts.Dialer
+	if dialer == nil {
+		dialer = websocket.DefaultDialer
+	}
+
+	ws, _, err := dialer.Dial(fmt.Sprintf("wss://%s/?v=4", server.Endpoint), http.Header{})
+	if err != nil {
+		return nil, fmt.Errorf("cord/voice: dialing voice gateway: %s", err)
+	}
+
+	vc := &VoiceConn{ws: ws}
+	if err := vc.handshake(guildID, state.UserID, state.SessionID, server.Token); err != nil {
+		ws.Close()
+		return nil, err
+	}
+
+	go vc.readLoop()
+
+	return vc, nil
+}
+
+// Disconnect leaves the voice channel in guildID, if any, without ever
+// requiring a VoiceConn. Sends a nil ChannelID, mirroring Discord's own
+// representation of leaving voice.
+func Disconnect(session cord.Socket, guildID string) error {
+	if err := session.Send(cord.VoiceStateUpdate, &model.UpdateVoiceState{
+		GuildID:   guildID,
+		ChannelID: nil,
+	}); err != nil {
+		return fmt.Errorf("cord/voice: sending voice state update: %s", err)
+	}
+	return nil
+}
+
+// handshake performs the Identify -> Ready -> IP discovery ->
+// Select Protocol -> Session Description exchange.
+func (v *VoiceConn) handshake(guildID, userID, sessionID, token string) error {
+	hello, err := v.readOp(opHello)
+	if err != nil {
+		return err
+	}
+	var h helloData
+	if err := json.Unmarshal(hello, &h); err != nil {
+		return fmt.Errorf("cord/voice: decoding hello: %s", err)
+	}
+	go v.pace(time.Duration(h.HeartbeatInterval) * time.Millisecond)
+
+	if err := v.send(opIdentify, identifyData{
+		ServerID:  guildID,
+		UserID:    userID,
+		SessionID: sessionID,
+		Token:     token,
+	}); err != nil {
+		return err
+	}
+
+	readyRaw, err := v.readOp(opReady)
+	if err != nil {
+		return err
+	}
+	var ready readyData
+	if err := json.Unmarshal(readyRaw, &ready); err != nil {
+		return fmt.Errorf("cord/voice: decoding ready: %s", err)
+	}
+	v.ssrc = ready.SSRC
+
+	udpAddr, err := net.ResolveUDPAddr("udp", fmt.Sprintf("%s:%d", ready.IP, ready.Port))
+	if err != nil {
+		return fmt.Errorf("cord/voice: resolving udp address: %s", err)
+	}
+	v.udp, err = net.DialUDP("udp", nil, udpAddr)
+	if err != nil {
+		return fmt.Errorf("cord/voice: dialing udp: %s", err)
+	}
+
+	myAddr, myPort, err := discoverIP(v.udp, v.ssrc)
+	if err != nil {
+		return err
+	}
+
+	if err := v.send(opSelectProtocol, selectProtocolData{
+		Protocol: "udp",
+		Data: selectProtocolDataBody{
+			Address: myAddr,
+			Port:    myPort,
+			Mode:    "xsalsa20_poly1305",
+		},
+	}); err != nil {
+		return err
+	}
+
+	descRaw, err := v.readOp(opSessionDescription)
+	if err != nil {
+		return err
+	}
+	var desc sessionDescriptionData
+	if err := json.Unmarshal(descRaw, &desc); err != nil {
+		return fmt.Errorf("cord/voice: decoding session description: %s", err)
+	}
+	v.secretKey = desc.SecretKey
+
+	return nil
+}
+
+// pace sends a heartbeat on the voice websocket every interval until the
+// connection is closed.
+func (v *VoiceConn) pace(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if atomic.LoadInt32(&v.closed) == 1 {
+			return
+		}
+		if err := v.send(opHeartbeat, time.Now().UnixNano()); err != nil {
+			return
+		}
+	}
+}
+
+// send marshals data and writes it to the voice websocket under op.
+func (v *VoiceConn) send(op operation, data interface{}) error {
+	b, err := marshalPayload(op, data)
+	if err != nil {
+		return fmt.Errorf("cord/voice: marshaling payload: %s", err)
+	}
+
+	return v.ws.WriteMessage(websocket.TextMessage, b)
+}
+
+// readOp blocks until a frame with the given opcode arrives, discarding
+// any others (e.g. speaking notifications) in between.
+func (v *VoiceConn) readOp(op operation) (json.RawMessage, error) {
+	for {
+		_, message, err := v.ws.ReadMessage()
+		if err != nil {
+			return nil, fmt.Errorf("cord/voice: reading voice gateway: %s", err)
+		}
+
+		var p payload
+		if err := json.Unmarshal(message, &p); err != nil {
+			return nil, fmt.Errorf("cord/voice: decoding voice gateway frame: %s", err)
+		}
+
+		if p.Operation == op {
+			return p.Data, nil
+		}
+	}
+}
+
+// readLoop drains the voice websocket for as long as the connection is
+// open. Nothing past the handshake calls ws.ReadMessage otherwise, which
+// means heartbeat acks, RESUMED, and gorilla/websocket's own control-frame
+// handling (pings, close frames) would never run, leaving a degraded or
+// dead connection undetectable until a caller happened to see a write
+// fail. It discards every frame it reads, heartbeat acks included, and
+// exits once the socket errors, e.g. because Close tore it down.
+func (v *VoiceConn) readLoop() {
+	for {
+		if _, _, err := v.ws.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+// Speaking tells Discord whether we're currently transmitting audio.
+func (v *VoiceConn) Speaking(speaking bool) error {
+	return v.send(opSpeaking, speakingData{Speaking: speaking, SSRC: v.ssrc})
+}
+
+// SendOpus encrypts and transmits a single Opus frame over the voice UDP
+// socket, advancing the RTP sequence number and timestamp.
+func (v *VoiceConn) SendOpus(frame []byte) error {
+	header := rtpHeader(v.sequence, v.timestamp, v.ssrc)
+
+	var nonce [24]byte
+	copy(nonce[:], header)
+
+	encrypted := secretbox.Seal(nil, frame, &nonce, &v.secretKey)
+	packet := append(header, encrypted...)
+
+	if _, err := v.udp.Write(packet); err != nil {
+		return fmt.Errorf("cord/voice: writing rtp packet: %s", err)
+	}
+
+	v.sequence++
+	v.timestamp += 960 // 20ms of 48kHz audio
+
+	return nil
+}
+
+// Close tears down the voice websocket and UDP socket.
+func (v *VoiceConn) Close() error {
+	atomic.StoreInt32(&v.closed, 1)
+
+	var firstErr error
+	if v.udp != nil {
+		if err := v.udp.Close(); err != nil {
+			firstErr = err
+		}
+	}
+	if v.ws != nil {
+		if err := v.ws.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -3,10 +3,13 @@ package cord
 import (
 	"bytes"
 	"compress/zlib"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"runtime"
 	"strconv"
 	"sync/atomic"
@@ -19,6 +22,10 @@ import (
 	"github.com/gorilla/websocket"
 )
 
+// errSocketClosed is returned by SendCtx when it's called after Close,
+// which atomically swaps the Websocket's live wsConn out for nil.
+var errSocketClosed = errors.New("cord/websocket: socket is closed")
+
 // WsOptions is passed to New() to configure the websocket setup.
 type WsOptions struct {
 	// Handshake packet to send to the server. Note that `compress` and
@@ -30,11 +37,18 @@ type WsOptions struct {
 	Timeout time.Duration
 
 	// Backoff determines how long to wait between reconnections to the
-	// websocket server. Defaults to an exponential backoff.
+	// websocket server. Defaults to an exponential backoff. If this
+	// WsOptions is reused across multiple Websockets (as ShardManager
+	// does, one shard per shallow copy of the options), a supplied
+	// Backoff is shared between them and must be safe for concurrent use
+	// by multiple goroutines; leave it nil to have each shard get its
+	// own exponential backoff instead.
 	Backoff backoff.BackOff
 
 	// Dialer to use for the websocket. Defaults to a dialer with the
-	// `timeout` duration.
+	// `timeout` duration. The same sharing caveat as Backoff applies: a
+	// Dialer supplied to options reused across shards must tolerate
+	// concurrent Dial calls (gorilla/websocket's Dialer does).
 	Dialer *websocket.Dialer
 
 	// The retriever to get the gateway to connect to. Defaults to the
@@ -46,6 +60,41 @@ type WsOptions struct {
 
 	// Headers to send in the websocket handshake.
 	Header http.Header
+
+	// JSON is the codec used to (un)marshal gateway frames and event
+	// bodies. Defaults to the stdlib; swap in a faster driver (easyjson,
+	// jsoniter, segmentio/encoding) to avoid forking the package.
+	JSON JSONDriver
+
+	// Compression selects how the gateway connection is compressed.
+	// Defaults to PerFrame.
+	Compression Compression
+
+	// OnConnect, if set, is called after a brand new session has been
+	// established with Discord, once the Ready event has been received.
+	// Use it to rebuild presence, re-register slash commands, or
+	// otherwise reinitialize state that doesn't survive into a fresh
+	// session. It runs on its own goroutine, since the write pump isn't
+	// started yet at this point in connection setup and a callback that
+	// calls Send would otherwise block forever.
+	OnConnect func()
+
+	// OnDisconnect, if set, is called with the triggering error whenever
+	// the connection is disrupted and about to be reconnected, before
+	// the reconnect backoff is applied. Use it to log session lifecycle
+	// without polling Errs() for a DisruptionError.
+	OnDisconnect func(err error)
+
+	// OnResume, if set, is called after a previous session has been
+	// successfully resumed, as opposed to a new one being started via
+	// OnConnect. Like OnConnect, it runs on its own goroutine.
+	OnResume func()
+
+	// DeferStart, if true, makes New return without connecting to
+	// Discord. Call Start once setup that must happen before the first
+	// handshake (e.g. AddIntent) is done. Defaults to false, where New
+	// starts connecting before it returns.
+	DeferStart bool
 }
 
 func (w *WsOptions) fillDefaults(token string) {
@@ -84,11 +133,21 @@ func (w *WsOptions) fillDefaults(token string) {
 		w.Debugger = nilDebugger{}
 	}
 
-	w.Handshake.Compress = true
+	if w.JSON == nil {
+		w.JSON = stdJSONDriver{}
+	}
+
+	// Stream mode is signaled via the gateway URL's `compress` query
+	// parameter (see gatewayURL), not this flag; the Identify payload's
+	// `compress` field only concerns PerFrame's per-packet compression.
+	w.Handshake.Compress = w.Compression == PerFrame
 	w.Handshake.Token = token
-	w.Handshake.Properties = model.HandshakeProperties{
-		OS:      runtime.GOOS,
-		Browser: "Cord 1.0",
+
+	if w.Handshake.Properties == (model.HandshakeProperties{}) {
+		w.Handshake.Properties = model.HandshakeProperties{
+			OS:      runtime.GOOS,
+			Browser: "Cord 1.0",
+		}
 	}
 }
 
@@ -99,6 +158,14 @@ func (w *WsOptions) fillDefaults(token string) {
 type wsConn struct {
 	ws    *websocket.Conn
 	queue *queue
+
+	// decompressor is non-nil only in Compression == Stream mode, where
+	// it holds the single zlib stream alive for this connection.
+	decompressor *streamDecompressor
+
+	// pacemaker is nil until the connection has completed its handshake,
+	// at which point it drives that connection's heartbeat schedule.
+	pacemaker *pacemaker
 }
 
 // Close closes the associated websocket and queue.
@@ -108,6 +175,9 @@ func (w *wsConn) Close() error {
 	}
 
 	w.queue.Close()
+	if w.pacemaker != nil {
+		w.pacemaker.Stop()
+	}
 	if w.ws != nil {
 		return w.ws.Close()
 	}
@@ -149,15 +219,25 @@ type Websocket struct {
 	events emitter
 
 	// ws points to a wsConn, atomically updated
-	ws        unsafe.Pointer
-	sessionID unsafe.Pointer
-	lastSeq   uint64 // atomically updated
-	errs      chan error
+	ws         unsafe.Pointer
+	sessionID  unsafe.Pointer
+	selfUserID unsafe.Pointer
+	lastSeq    uint64 // atomically updated
+	started    int32  // atomically updated
+	errs       chan error
 }
 
 // start boots the websocket asynchronously.
 func (w *Websocket) start() { go w.restart(nil, nil) }
 
+// Start implements Socket.Start. It's a no-op past the first call, so it's
+// safe to call unconditionally even when DeferStart wasn't set.
+func (w *Websocket) Start() {
+	if atomic.CompareAndSwapInt32(&w.started, 0, 1) {
+		w.start()
+	}
+}
+
 // restart closes the server and attempts to reconnect to Discord. It takes
 // an optional error to log down. If the error is of type FatalError, restart
 // will exit after sending it without attempting to reconnect.
@@ -175,6 +255,9 @@ func (w *Websocket) restart(err error, prev *wsConn) {
 		return
 	} else if err != nil {
 		w.sendErr(DisruptionError{err})
+		if w.opts.OnDisconnect != nil {
+			w.opts.OnDisconnect(err)
+		}
 		time.Sleep(w.opts.Backoff.NextBackOff())
 	}
 
@@ -193,24 +276,71 @@ type sessionDetails struct {
 	Heartbeat uint
 }
 
+// gatewayURL adds transport-compression query parameters for Stream mode.
+// PerFrame and None need no URL changes, since they're controlled
+// entirely by the Identify payload's `compress` field.
+func (w *Websocket) gatewayURL(gateway string) (string, error) {
+	if w.opts.Compression != Stream {
+		return gateway, nil
+	}
+
+	u, err := url.Parse(gateway)
+	if err != nil {
+		return "", err
+	}
+
+	q := u.Query()
+	q.Set("compress", "zlib-stream")
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
+
 func (w *Websocket) establishSocketConnection(gateway string, cnx *wsConn) {
 	w.opts.Debugger.Connecting(gateway)
-	ws, _, err := w.opts.Dialer.Dial(gateway, w.opts.Header)
+
+	dialURL, err := w.gatewayURL(gateway)
+	if err != nil {
+		w.restart(err, cnx)
+		return
+	}
+
+	ws, _, err := w.opts.Dialer.Dial(dialURL, w.opts.Header)
 	if err != nil {
 		w.restart(err, cnx)
 		return
 	}
 
-	details, err := w.runHandshake(ws)
+	var decompressor *streamDecompressor
+	if w.opts.Compression == Stream {
+		// A new TCP connection starts a fresh zlib stream. Create it
+		// now so it's already live for the handshake's own reads: the
+		// Ready payload is exactly the large, multi-frame response this
+		// mode exists to handle.
+		decompressor = newStreamDecompressor()
+	}
+
+	details, err := w.runHandshake(ws, decompressor)
 	if err != nil {
 		w.restart(err, cnx)
 		return
 	}
 
+	// time.NewTicker panics on a non-positive interval, and Heartbeat
+	// comes straight off the wire (model.Ready/model.Resumed); treat a
+	// missing or zero value as just another failed handshake rather than
+	// trusting it and crashing the process in newPacemaker below.
+	if details.Heartbeat == 0 {
+		w.restart(fmt.Errorf("cord/websocket: server reported a zero heartbeat interval"), cnx)
+		return
+	}
+
 	next := &wsConn{
-		queue: cnx.queue,
-		ws:    ws,
+		queue:        cnx.queue,
+		ws:           ws,
+		decompressor: decompressor,
 	}
+	next.pacemaker = newPacemaker(time.Duration(details.Heartbeat) * time.Millisecond)
 
 	// Note: we store a new pointer rather than updating the cnx because
 	// someone else might have read the wsConn pointer in the meantime.
@@ -218,15 +348,41 @@ func (w *Websocket) establishSocketConnection(gateway string, cnx *wsConn) {
 	w.opts.Backoff.Reset()
 
 	atomic.StorePointer(&w.sessionID, unsafe.Pointer(&details.SessionID))
-	interval := time.Duration(details.Heartbeat) * time.Millisecond
 
+	go next.pacemaker.Pace()
 	go w.readPump(next)
-	go w.writePump(next, interval)
+	go w.writePump(next)
+}
+
+// readHandshakeFrame reads a single logical frame off ws, routing it
+// through decompressor first when the connection is in Stream mode, same
+// as readPump does for steady-state traffic. It's what lets
+// invokeWithResponse decode a Ready payload spread across several
+// zlib-stream frames during the handshake itself.
+func (w *Websocket) readHandshakeFrame(ws *websocket.Conn, decompressor *streamDecompressor) ([]byte, error) {
+	for {
+		_, message, err := ws.ReadMessage()
+		if err != nil {
+			return nil, err
+		}
+
+		if decompressor == nil {
+			return message, nil
+		}
+
+		message, err = decompressor.Feed(message)
+		if err != nil {
+			return nil, err
+		}
+		if message != nil {
+			return message, nil
+		}
+	}
 }
 
 // invokeWithResponse attempts to write the operation to the websocket and
 // immediately read a result back with a timeout.
-func (w *Websocket) invokeWithResponse(ws *websocket.Conn, op Operation, data json.Marshaler) (*Payload, error) {
+func (w *Websocket) invokeWithResponse(ws *websocket.Conn, decompressor *streamDecompressor, op Operation, data json.Marshaler) (*Payload, error) {
 	data, err := w.marshalPayload(op, data)
 	if err != nil {
 		return nil, FatalError{err}
@@ -237,7 +393,7 @@ func (w *Websocket) invokeWithResponse(ws *websocket.Conn, op Operation, data js
 	}
 
 	ws.SetReadDeadline(time.Now().Add(w.opts.Timeout))
-	_, message, err := ws.ReadMessage()
+	message, err := w.readHandshakeFrame(ws, decompressor)
 	if err != nil {
 		return nil, err
 	}
@@ -248,8 +404,8 @@ func (w *Websocket) invokeWithResponse(ws *websocket.Conn, op Operation, data js
 // runHandshakeResume attempts to continue a previously disconnected session
 // on the websocket. It calls back to runHandshakeNew if the session is
 // deemed invalid.
-func (w *Websocket) runHandshakeResume(ws *websocket.Conn, sessionID string) (details sessionDetails, err error) {
-	payload, err := w.invokeWithResponse(ws, Resume, &model.Resume{
+func (w *Websocket) runHandshakeResume(ws *websocket.Conn, decompressor *streamDecompressor, sessionID string) (details sessionDetails, err error) {
+	payload, err := w.invokeWithResponse(ws, decompressor, Resume, &model.Resume{
 		Token:     w.opts.Handshake.Token,
 		SessionID: sessionID,
 		Sequence:  atomic.LoadUint64(&w.lastSeq),
@@ -268,12 +424,20 @@ func (w *Websocket) runHandshakeResume(ws *websocket.Conn, sessionID string) (de
 		err = events.Resumed(func(r *model.Resumed) {
 			details.Heartbeat = r.HeartbeatInterval
 			details.SessionID = sessionID
-		}).Invoke(payload.Data)
-		go w.events.Dispatch(payload.Event, payload.Data)
+		}).Invoke(w.opts.JSON.Unmarshal, payload.Data)
+		go w.events.Dispatch(payload.Event, w.opts.JSON.Unmarshal, payload.Data)
+
+		if w.opts.OnResume != nil {
+			// Run off the handshake goroutine: establishSocketConnection
+			// hasn't started the write pump yet, so a callback that
+			// calls Send would enqueue a message nothing will ever
+			// drain and block forever.
+			go w.opts.OnResume()
+		}
 		return details, nil
 
 	case InvalidSession:
-		return w.runHandshakeNew(ws)
+		return w.runHandshakeNew(ws, decompressor)
 	default:
 		return details, fmt.Errorf("cord/websocket: expected to get opcode %d or %d, %d",
 			Dispatch,
@@ -284,8 +448,8 @@ func (w *Websocket) runHandshakeResume(ws *websocket.Conn, sessionID string) (de
 }
 
 // runHandshakeNew attempts to authenticate a new session on the websocket.
-func (w *Websocket) runHandshakeNew(ws *websocket.Conn) (details sessionDetails, err error) {
-	payload, err := w.invokeWithResponse(ws, Identify, w.opts.Handshake)
+func (w *Websocket) runHandshakeNew(ws *websocket.Conn, decompressor *streamDecompressor) (details sessionDetails, err error) {
+	payload, err := w.invokeWithResponse(ws, decompressor, Identify, w.opts.Handshake)
 
 	// If the token the user provided is invalid, die, we can't do anything.
 	if wserr, ok := err.(*websocket.CloseError); ok && wserr.Code == 4004 {
@@ -300,21 +464,28 @@ func (w *Websocket) runHandshakeNew(ws *websocket.Conn) (details sessionDetails,
 	err = events.Ready(func(r *model.Ready) {
 		details.Heartbeat = r.HeartbeatInterval
 		details.SessionID = r.SessionID
-	}).Invoke(payload.Data)
-	go w.events.Dispatch(payload.Event, payload.Data)
+		atomic.StorePointer(&w.selfUserID, unsafe.Pointer(&r.User.ID))
+	}).Invoke(w.opts.JSON.Unmarshal, payload.Data)
+	go w.events.Dispatch(payload.Event, w.opts.JSON.Unmarshal, payload.Data)
+
+	if err == nil && w.opts.OnConnect != nil {
+		// See the matching comment in runHandshakeResume: the write
+		// pump isn't running yet, so this must not block waiting on it.
+		go w.opts.OnConnect()
+	}
 
 	return details, err
 }
 
 // sendHandshake dispatches either an Identify or Resume packet on the
 // connection, depending whether we were connected before.
-func (w *Websocket) runHandshake(ws *websocket.Conn) (sessionDetails, error) {
+func (w *Websocket) runHandshake(ws *websocket.Conn, decompressor *streamDecompressor) (sessionDetails, error) {
 	sid := (*string)(atomic.LoadPointer(&w.sessionID))
 
 	if sid == nil {
-		return w.runHandshakeNew(ws)
+		return w.runHandshakeNew(ws, decompressor)
 	} else {
-		return w.runHandshakeResume(ws, *sid)
+		return w.runHandshakeResume(ws, decompressor, *sid)
 	}
 }
 
@@ -332,9 +503,23 @@ func (w *Websocket) readPump(cnx *wsConn) {
 
 		// Control frames won't have associated messages, only care about
 		// binary or text messages.
-		if kind == websocket.TextMessage || kind == websocket.BinaryMessage {
-			go w.handleIncoming(message, cnx)
+		if kind != websocket.TextMessage && kind != websocket.BinaryMessage {
+			continue
 		}
+
+		if cnx.decompressor != nil {
+			message, err = cnx.decompressor.Feed(message)
+			if err != nil {
+				w.restart(err, cnx)
+				return
+			}
+			if message == nil {
+				// Not yet at a SYNC_FLUSH boundary; wait for more frames.
+				continue
+			}
+		}
+
+		go w.handleIncoming(message, cnx)
 	}
 }
 
@@ -349,26 +534,26 @@ func (w *Websocket) writeMessage(ws *websocket.Conn, data json.Marshaler) error
 	return ws.WriteMessage(websocket.TextMessage, bytes)
 }
 
-func (w *Websocket) writePump(cnx *wsConn, heartbeat time.Duration) {
-	ticker := time.NewTicker(heartbeat)
-	defer ticker.Stop()
-
+func (w *Websocket) writePump(cnx *wsConn) {
 	for {
 		var err error
 
 		select {
-		case <-ticker.C:
+		case <-cnx.pacemaker.Beat():
 			seq := atomic.LoadUint64(&w.lastSeq)
 			err = w.writeMessage(cnx.ws, &Payload{
 				Operation: Heartbeat,
 				Data:      json.RawMessage(strconv.FormatUint(seq, 10)),
 			})
 
-		case msg, ok := <-cnx.queue.Poll():
-			if !ok {
-				return
-			}
+		case <-cnx.pacemaker.Dead():
+			w.restart(PacemakerDeadError{}, cnx)
+			return
 
+		case <-cnx.queue.Done():
+			return
+
+		case msg := <-cnx.queue.Poll():
 			err = w.writeMessage(cnx.ws, msg.data)
 			msg.result <- err
 		}
@@ -391,7 +576,7 @@ func (w *Websocket) unmarshalPayload(b []byte) (*Payload, error) {
 
 	w.opts.Debugger.Incoming(b)
 
-	wrapper := &Payload{}
+	wrapper := &Payload{driver: w.opts.JSON}
 	if err := wrapper.UnmarshalJSON(b); err != nil {
 		return nil, err
 	}
@@ -417,11 +602,13 @@ func (w *Websocket) handleIncoming(b []byte, cnx *wsConn) {
 	switch wrapper.Operation {
 	case Dispatch:
 		atomic.StoreUint64(&w.lastSeq, wrapper.Sequence)
-		if err := w.events.Dispatch(wrapper.Event, wrapper.Data); err != nil {
+		if err := w.events.Dispatch(wrapper.Event, w.opts.JSON.Unmarshal, wrapper.Data); err != nil {
 			w.sendErr(fmt.Errorf("cord/websocket: error dispatching event: %s", err))
 		}
 	case Reconnect:
 		w.restart(nil, cnx)
+	case HeartbeatAck:
+		cnx.pacemaker.Echo()
 	case InvalidSession:
 		atomic.StorePointer(&w.sessionID, unsafe.Pointer(nil))
 		w.restart(fmt.Errorf("cord/websocket: invalid session detected"), cnx)
@@ -442,6 +629,31 @@ func (w *Websocket) Once(h events.Handler) { w.events.Once(h) }
 // Errs implements Socket.Errs
 func (w *Websocket) Errs() <-chan error { return w.errs }
 
+// Options returns the WsOptions this Websocket was constructed with, so
+// other packages layered on top of Socket can reuse its settings instead
+// of configuring their own (voice reuses its Dialer).
+func (w *Websocket) Options() *WsOptions { return w.opts }
+
+// SelfUserID returns the bot's own user id, as reported on the most
+// recent Ready. It's empty until the initial handshake completes.
+func (w *Websocket) SelfUserID() string {
+	id := (*string)(atomic.LoadPointer(&w.selfUserID))
+	if id == nil {
+		return ""
+	}
+	return *id
+}
+
+// AddIntent adds the given intent(s) to the handshake's requested
+// gateway intents. New starts the handshake goroutine before it returns,
+// so there's no safe window to call this afterward; set WsOptions.
+// DeferStart and call AddIntent before Start instead. Intents are fixed
+// for the lifetime of the session and can't be changed once Start has
+// been called.
+func (w *Websocket) AddIntent(i model.Intents) {
+	w.opts.Handshake.Intents |= i
+}
+
 // marshalPayload marshals the provided data for transport over the socket.
 func (w *Websocket) marshalPayload(op Operation, data json.Marshaler) (*Payload, error) {
 	bytes, err := data.MarshalJSON()
@@ -452,11 +664,17 @@ func (w *Websocket) marshalPayload(op Operation, data json.Marshaler) (*Payload,
 	return &Payload{
 		Operation: op,
 		Data:      bytes,
+		driver:    w.opts.JSON,
 	}, nil
 }
 
 // Send implements Socket.Send
 func (w *Websocket) Send(op Operation, data json.Marshaler) error {
+	return w.SendCtx(context.Background(), op, data)
+}
+
+// SendCtx implements Socket.SendCtx
+func (w *Websocket) SendCtx(ctx context.Context, op Operation, data json.Marshaler) error {
 	payload, err := w.marshalPayload(op, data)
 	if err != nil {
 		return err
@@ -464,8 +682,19 @@ func (w *Websocket) Send(op Operation, data json.Marshaler) error {
 
 	result := make(chan error, 1)
 	cnx := (*wsConn)(atomic.LoadPointer(&w.ws))
-	cnx.queue.Push(&queuedMessage{payload, result})
-	return <-result
+	if cnx == nil {
+		return errSocketClosed
+	}
+	if err := cnx.queue.Push(ctx, &queuedMessage{payload, result}); err != nil {
+		return err
+	}
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 // Close frees resources associated with the websocket.